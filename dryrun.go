@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/grdl/influx-converter/pkg/backend"
+)
+
+// dryRunSampler prints at most n points in line-protocol format across
+// however many writer goroutines are converting batches concurrently, for
+// --dry-run.
+type dryRunSampler struct {
+	mu        sync.Mutex
+	remaining int
+}
+
+func newDryRunSampler(n int) *dryRunSampler {
+	return &dryRunSampler{remaining: n}
+}
+
+func (s *dryRunSampler) sample(points []backend.Point) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, p := range points {
+		if s.remaining <= 0 {
+			return
+		}
+
+		fmt.Println(p.LineProtocol())
+		s.remaining--
+	}
+}
@@ -1,242 +1,469 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"fmt"
 	"log"
-	"net/url"
+	"math/rand"
+	"sync"
 	"time"
 
 	"gopkg.in/alecthomas/kingpin.v2"
 
-	"github.com/influxdata/influxdb1-client"
-	"github.com/influxdata/influxdb1-client/models"
+	"github.com/grdl/influx-converter/pkg/backend"
+	"github.com/grdl/influx-converter/pkg/mapping"
 )
 
-var (
-	batchSize      = kingpin.Flag("batch-size", "Number of metrics inserted at a time").Default("10000").Int()
-	sourceUsername = kingpin.Flag("source-username", "Username for the source InfluxDB.").Required().String()
-	sourcePassword = kingpin.Flag("source-password", "Password for the source InfluxDB.").Required().String()
-	targetUsername = kingpin.Flag("target-username", "Username for the target InfluxDB. If missing, source-username is used.").Default(*sourceUsername).String()
-	targetPassword = kingpin.Flag("target-password", "Password for the target InfluxDB. If missing, source-password is used.").Default(*sourcePassword).String()
-	sourceURL      = kingpin.Flag("source-url", "URL of the source InfluxDB.").Default("https://influxdb.hq.grdl.pl").String()
-	targetURL      = kingpin.Flag("target-url", "URL of the target InfluxDB. If missing, source-url is used.").Default(*sourceURL).String()
-	sourceDB       = kingpin.Flag("source-db", "Name of the source database.").Default("nestats").String()
-	targetDB       = kingpin.Flag("target-db", "Name of the target database.").Default("prometheus").String()
+const (
+	backendTypeInflux1               = "influx1"
+	backendTypeInflux2               = "influx2"
+	backendTypePrometheusRemoteWrite = "prometheus-remote-write"
 )
 
-var (
-	defaultTags = map[string]string{
-		"job":      "pronestheus",
-		"instance": "pronestheus:2112",
-		"name":     "Living-Room",
-		"id":       "JyHyG8n7kBXBV0_KHqQhNsmUnpmzy3o_",
-	}
-
-	//Columns to convert:
-
-	// inside:
-	// has_leaf -> nest_leaf
-	// humidity ->  nest_humidity
-	// is_heating -> nest_heating
-	// target -> nest_target_temp
-	// temperature -> nest_current_temp
-
-	insideQuery      = "select has_leaf as nest_leaf, humidity as nest_humidity, is_heating as nest_heating, target as nest_target_temp, temperature as nest_current_temp from inside where time > now() -10h"
-	insideCountQuery = "select count(*) from inside where time > now() -10h"
-
-	// outside:
-	// humidity -> nest_weather_humidity
-	// pressure -> nest_weather_pressure
-	// temperature -> nest_weather_temp
+const (
+	maxBatchRetries     = 5
+	initialBatchBackoff = 500 * time.Millisecond
+	maxBatchBackoff     = 30 * time.Second
+)
 
-	outsideQuery      = "select humidity as nest_weather_humidity, pressure as nest_weather_pressure, temperature as nest_weather_temp from outside where time > now() -10h"
-	outsideCountQuery = "select count(*) from outside where time > now() -10h"
+var (
+	configPath = kingpin.Flag("config", "Path to a YAML file declaring the source-to-target column mappings.").Required().String()
+
+	dryRun           = kingpin.Flag("dry-run", "Run the query and conversion pipeline but skip writing; print a sample of the generated points instead.").Bool()
+	dryRunSampleSize = kingpin.Flag("dry-run-sample-size", "Number of sample points to print per mapping in --dry-run mode.").Default("10").Int()
+	verify           = kingpin.Flag("verify", "After writing each batch, verify it against the target with a count(*) and a random-sample equality check.").Bool()
+
+	batchSize          = kingpin.Flag("batch-size", "Number of metrics inserted at a time").Default("10000").Int()
+	writerConcurrency  = kingpin.Flag("writer-concurrency", "Number of concurrent writer goroutines draining converted batches.").Default("4").Int()
+	maxInflightBatches = kingpin.Flag("max-inflight-batches", "Maximum number of converted batches buffered between the query reader and the writer pool.").Default("8").Int()
+
+	sourceType     = kingpin.Flag("source-type", "Type of the source backend.").Default(backendTypeInflux1).Enum(backendTypeInflux1, backendTypeInflux2)
+	sourceUsername = kingpin.Flag("source-username", "Username for an InfluxDB 1.x source.").String()
+	sourcePassword = kingpin.Flag("source-password", "Password for an InfluxDB 1.x source.").String()
+	sourceURL      = kingpin.Flag("source-url", "URL of the source database.").Default("https://influxdb.hq.grdl.pl").String()
+	sourceDB       = kingpin.Flag("source-db", "Name of the source database. Only used for --source-type=influx1.").Default("nestats").String()
+	sourceToken    = kingpin.Flag("source-token", "Auth token for an InfluxDB 2.x source.").String()
+	sourceOrg      = kingpin.Flag("source-org", "Organization for an InfluxDB 2.x source.").String()
+	sourceBucket   = kingpin.Flag("source-bucket", "Bucket for an InfluxDB 2.x source.").String()
+
+	targetType           = kingpin.Flag("target-type", "Type of the target backend.").Default(backendTypeInflux1).Enum(backendTypeInflux1, backendTypeInflux2, backendTypePrometheusRemoteWrite)
+	targetUsername       = kingpin.Flag("target-username", "Username for an InfluxDB 1.x target. If missing, source-username is used.").String()
+	targetPassword       = kingpin.Flag("target-password", "Password for an InfluxDB 1.x target. If missing, source-password is used.").String()
+	targetURL            = kingpin.Flag("target-url", "URL of the target database. If missing, source-url is used.").String()
+	targetDB             = kingpin.Flag("target-db", "Name of the target database. Only used for --target-type=influx1.").Default("prometheus").String()
+	targetToken          = kingpin.Flag("target-token", "Auth token for an InfluxDB 2.x target.").String()
+	targetOrg            = kingpin.Flag("target-org", "Organization for an InfluxDB 2.x target.").String()
+	targetBucket         = kingpin.Flag("target-bucket", "Bucket for an InfluxDB 2.x target.").String()
+	targetRemoteWriteURL = kingpin.Flag("target-remote-write-url", "URL of the Prometheus remote_write endpoint. Required when --target-type=prometheus-remote-write.").String()
 )
 
 type Converter struct {
-	SourceClient *client.Client
-	TargetClient *client.Client
+	Source backend.Source
+	Sink   backend.Sink
+
+	// VerifyReport accumulates per-measurement written/expected counts when
+	// --verify is set. nil otherwise.
+	VerifyReport *verifyReport
 }
 
 func main() {
 	kingpin.Parse()
 
+	// Flag defaults are fixed before parsing, so the target-* fallbacks to
+	// their source-* counterparts are applied here instead.
+	if *targetUsername == "" {
+		*targetUsername = *sourceUsername
+	}
+	if *targetPassword == "" {
+		*targetPassword = *sourcePassword
+	}
+	if *targetURL == "" {
+		*targetURL = *sourceURL
+	}
+
+	cfg, err := mapping.Load(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	c, err := NewConverter()
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	c.RunOnTable(insideQuery, insideCountQuery)
-	c.RunOnTable(outsideQuery, outsideCountQuery)
+	for i := range cfg.Mappings {
+		c.RunOnTable(&cfg.Mappings[i])
+	}
+
+	if c.VerifyReport != nil {
+		c.VerifyReport.log()
+	}
 }
 
 func NewConverter() (*Converter, error) {
-	sourceHost, err := url.Parse(*sourceURL)
+	source, err := newSource()
 	if err != nil {
 		return nil, err
 	}
 
-	targetHost, err := url.Parse(*targetURL)
+	sink, err := newSink()
 	if err != nil {
 		return nil, err
 	}
 
-	sourceConf := client.Config{
-		URL:       *sourceHost,
-		Username:  *sourceUsername,
-		Password:  *sourcePassword,
-		Precision: "s", // second precision is enough
+	converter := &Converter{Source: source, Sink: sink}
+	if *verify {
+		converter.VerifyReport = newVerifyReport()
 	}
 
-	targetConf := client.Config{
-		URL:       *targetHost,
-		Username:  *targetUsername,
-		Password:  *targetPassword,
-		Precision: "s", // second precision is enough
+	return converter, nil
+}
+
+func newSource() (backend.Source, error) {
+	switch *sourceType {
+	case backendTypeInflux2:
+		return backend.NewInflux2Backend(*sourceURL, *sourceToken, *sourceOrg, *sourceBucket), nil
+	default:
+		return backend.NewInflux1Backend(*sourceURL, *sourceUsername, *sourcePassword, *sourceDB, *batchSize)
 	}
+}
 
-	sourceClient, err := client.NewClient(sourceConf)
-	if err != nil {
-		return nil, err
+func newSink() (backend.Sink, error) {
+	switch *targetType {
+	case backendTypeInflux2:
+		return backend.NewInflux2Backend(*targetURL, *targetToken, *targetOrg, *targetBucket), nil
+	case backendTypePrometheusRemoteWrite:
+		if *targetRemoteWriteURL == "" {
+			return nil, fmt.Errorf("--target-remote-write-url is required when --target-type=%s", backendTypePrometheusRemoteWrite)
+		}
+		return backend.NewRemoteWriteSink(*targetRemoteWriteURL), nil
+	default:
+		return backend.NewInflux1Backend(*targetURL, *targetUsername, *targetPassword, *targetDB, *batchSize)
 	}
+}
 
-	targetClient, err := client.NewClient(targetConf)
+// firstRow runs query against src and returns just its first row (or a zero
+// Row if the query produced none), then cancels the query. Queries expected
+// to produce exactly one logical result - a count(*), a single-timestamp
+// sample lookup - can still come back as several Rows (e.g. one per field,
+// or one per matching series), and the backends stream rows over an
+// unbuffered channel; reading only the first without canceling would leave
+// their producer goroutine blocked forever trying to send the rest.
+func firstRow(ctx context.Context, src backend.Source, query string) (backend.Row, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	rows, err := src.Query(ctx, query)
 	if err != nil {
-		return nil, err
+		return backend.Row{}, err
 	}
 
-	return &Converter{
-		SourceClient: sourceClient,
-		TargetClient: targetClient,
-	}, nil
+	return <-rows, nil
 }
 
-func (c *Converter) RunOnTable(query string, countQuery string) {
+// RunOnTable streams m's query through the chunked query API, converts rows
+// into batches of points, and fans them out to a pool of writer-concurrency
+// goroutines. max-inflight-batches bounds the channel between the reader and
+// the writer pool, applying backpressure once the writers fall behind.
+func (c *Converter) RunOnTable(m *mapping.Mapping) {
+	ctx := context.Background()
+
+	var query, countQuery string
+	if *sourceType == backendTypeInflux2 {
+		query = m.FluxSelectQuery(*sourceBucket)
+		countQuery = m.FluxCountQuery(*sourceBucket)
+	} else {
+		query = m.SelectQuery()
+		countQuery = m.CountQuery()
+	}
+
 	log.Println("-------------------------------")
 	log.Printf("Running with query: %s\n", query)
 
-	result, err := c.Query(*sourceDB, countQuery)
+	countRow, err := firstRow(ctx, c.Source, countQuery)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if countRow.Err != nil {
+		log.Fatal(countRow.Err)
+	}
+
+	var totalRows float64
+	if len(countRow.Values) > 0 {
+		totalRows, err = backend.ToFloat64(countRow.Values[len(countRow.Values)-1])
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	log.Printf("Query counted %v metrics\n", totalRows)
+
+	rows, err := c.Source.Query(ctx, query)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	countedQuery := result.Values[0][1]
+	batches := make(chan []backend.Row, *maxInflightBatches)
+
+	go func() {
+		defer close(batches)
+
+		var batch []backend.Row
+		for row := range rows {
+			if row.Err != nil {
+				log.Fatal(row.Err)
+			}
+
+			batch = append(batch, row)
+			if len(batch) >= *batchSize {
+				batches <- batch
+				batch = nil
+			}
+		}
+
+		if len(batch) > 0 {
+			batches <- batch
+		}
+	}()
+
+	progress := newProgressTracker(totalRows)
+	sampler := newDryRunSampler(*dryRunSampleSize)
+
+	var wg sync.WaitGroup
+	for i := 0; i < *writerConcurrency; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for batch := range batches {
+				c.convertAndWrite(ctx, m, worker, batch, progress, sampler)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+}
 
-	result, err = c.Query(*sourceDB, query)
+func (c *Converter) convertAndWrite(ctx context.Context, m *mapping.Mapping, worker int, batch []backend.Row, progress *progressTracker, sampler *dryRunSampler) {
+	points, err := c.Convert(m, batch)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	countedResult := len(result.Values)
+	if *dryRun {
+		sampler.sample(points)
+		return
+	}
+
+	start := time.Now()
+
+	if err := c.writeWithRetry(ctx, points); err != nil {
+		log.Fatal(err)
+	}
+
+	elapsed := time.Since(start)
+	progress.reportBatch(worker, len(batch), elapsed)
+
+	if *verify {
+		c.verifyBatch(ctx, points)
+	}
+}
 
-	log.Printf("Query counted %s metrics and received %d metrics\n", countedQuery, countedResult)
+// verifyBatch re-queries the target for each measurement just written,
+// comparing its row count and a random sample against what was sent, to
+// catch points InfluxDB silently dropped (e.g. on a field-type conflict).
+// It's a best-effort check: targets that can't be queried (like
+// prometheus-remote-write) are skipped with a warning.
+func (c *Converter) verifyBatch(ctx context.Context, points []backend.Point) {
+	sink, ok := c.Sink.(backend.Source)
+	if !ok {
+		log.Println("verify: target backend cannot be queried, skipping")
+		return
+	}
 
-	var batches [][][]interface{}
-	for *batchSize < len(result.Values) {
-		result.Values, batches = result.Values[*batchSize:], append(batches, result.Values[0:*batchSize:*batchSize])
+	byMeasurement := make(map[string][]backend.Point)
+	for _, p := range points {
+		byMeasurement[p.Measurement] = append(byMeasurement[p.Measurement], p)
 	}
-	batches = append(batches, result.Values)
 
-	for i, batch := range batches {
-		log.Printf("Converting batch %d / %d\n", i+1, len(batches))
+	for measurement, written := range byMeasurement {
+		minTime, maxTime := written[0].Time, written[0].Time
+		for _, p := range written {
+			if p.Time.Before(minTime) {
+				minTime = p.Time
+			}
+			if p.Time.After(maxTime) {
+				maxTime = p.Time
+			}
+		}
 
-		points, err := c.Convert(result.Columns, batch)
+		countQuery := c.verifyCountQuery(measurement, minTime, maxTime)
+		countRow, err := firstRow(ctx, sink, countQuery)
 		if err != nil {
-			log.Fatal(err)
+			log.Printf("verify: counting %s: %v\n", measurement, err)
+			continue
+		}
+		if countRow.Err != nil {
+			log.Printf("verify: counting %s: %v\n", measurement, countRow.Err)
+			continue
 		}
 
-		log.Printf("Writing batch %d / %d\n", i+1, len(batches))
+		var expected int64
+		if len(countRow.Values) > 0 {
+			expected, err = backend.ToInt64(countRow.Values[len(countRow.Values)-1])
+			if err != nil {
+				log.Printf("verify: counting %s: %v\n", measurement, err)
+				continue
+			}
+		}
 
-		err = c.WritePoints(points)
+		c.VerifyReport.record(measurement, len(written), int(expected))
+
+		sample := written[rand.Intn(len(written))]
+		sampleQuery := c.verifySampleQuery(measurement, sample.Time)
+		sampleRow, err := firstRow(ctx, sink, sampleQuery)
 		if err != nil {
-			log.Fatal(err)
+			log.Printf("verify: sampling %s: %v\n", measurement, err)
+			continue
+		}
+		if sampleRow.Err != nil {
+			log.Printf("verify: sampling %s: %v\n", measurement, sampleRow.Err)
+			continue
+		}
+
+		if sampleRow.Values == nil {
+			log.Printf("verify: %s at %s is missing on the target - point was silently dropped\n", measurement, sample.Time)
+			continue
+		}
+
+		got, err := backend.ToFloat64(sampleRow.Values[len(sampleRow.Values)-1])
+		if err != nil {
+			log.Printf("verify: sampling %s: %v\n", measurement, err)
+			continue
+		}
+
+		if want := sample.Fields["value"].(float64); got != want {
+			log.Printf("verify: %s at %s: wrote %v, target has %v\n", measurement, sample.Time, want, got)
 		}
 	}
 }
 
-func (c *Converter) Query(database string, query string) (rows models.Row, err error) {
-	result := models.Row{}
-
-	q := client.Query{
-		Command:  query,
-		Database: database,
+// verifyCountQuery builds the query counting rows written to measurement
+// between minTime and maxTime (inclusive), in whichever query language the
+// target backend speaks.
+func (c *Converter) verifyCountQuery(measurement string, minTime, maxTime time.Time) string {
+	if *targetType == backendTypeInflux2 {
+		return fmt.Sprintf(
+			`from(bucket: %q) |> range(start: %s, stop: %s) |> filter(fn: (r) => r._measurement == %q) |> count()`,
+			*targetBucket, minTime.Format(time.RFC3339Nano), maxTime.Add(time.Nanosecond).Format(time.RFC3339Nano), measurement,
+		)
 	}
 
-	response, err := c.SourceClient.Query(q)
+	return fmt.Sprintf("select count(value) from %s where time >= %d and time <= %d", measurement, minTime.UnixNano(), maxTime.UnixNano())
+}
 
-	if err != nil {
-		return result, err
+// verifySampleQuery builds the query fetching the single value written to
+// measurement at the given time, in whichever query language the target
+// backend speaks.
+func (c *Converter) verifySampleQuery(measurement string, at time.Time) string {
+	if *targetType == backendTypeInflux2 {
+		return fmt.Sprintf(
+			`from(bucket: %q) |> range(start: %s, stop: %s) |> filter(fn: (r) => r._measurement == %q and r._time == time(v: %q))`,
+			*targetBucket, at.Format(time.RFC3339Nano), at.Add(time.Nanosecond).Format(time.RFC3339Nano), measurement, at.Format(time.RFC3339Nano),
+		)
 	}
 
-	if response.Error() != nil {
-		return result, response.Error()
-	}
+	return fmt.Sprintf("select value from %s where time = %d", measurement, at.UnixNano())
+}
 
-	if len(response.Results) != 1 && len(response.Results[0].Series) != 1 {
-		log.Fatalf("Something went wrong: received %d results and %d series in the first result from the query %s\n",
-			len(response.Results),
-			len(response.Results[0].Series),
-			query)
+// writeWithRetry calls Sink.Write, retrying with exponential backoff on
+// transient failures. RemoteWriteSink already retries internally (it has to,
+// to honor the remote_write endpoint's Retry-After header), so it's excluded
+// here to avoid compounding two independent backoff loops into dozens of
+// attempts on a sustained outage.
+func (c *Converter) writeWithRetry(ctx context.Context, points []backend.Point) error {
+	if _, ok := c.Sink.(backend.SelfRetrying); ok {
+		return c.Sink.Write(ctx, points)
 	}
 
-	result = response.Results[0].Series[0]
+	backoff := initialBatchBackoff
 
-	return result, nil
+	var lastErr error
+	for attempt := 0; attempt <= maxBatchRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+			if backoff > maxBatchBackoff {
+				backoff = maxBatchBackoff
+			}
+		}
+
+		err := c.Sink.Write(ctx, points)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+	}
+
+	return fmt.Errorf("writing batch: giving up after %d attempts: %w", maxBatchRetries+1, lastErr)
 }
 
-func (c *Converter) Convert(columns []string, batch [][]interface{}) (points []client.Point, err error) {
-	// Go over each column and convert it into a separate measurement
-	for _, values := range batch {
-		timeValue, err := values[0].(json.Number).Int64()
+// Convert turns a batch of rows selected by m's query into points, one per
+// metric column per row. Columns selected only to feed m's tag templates are
+// skipped as metric columns but are available to RenderTags.
+func (c *Converter) Convert(m *mapping.Mapping, batch []backend.Row) (points []backend.Point, err error) {
+	targetColumns := m.TargetColumns()
+
+	for _, row := range batch {
+		timeValue, err := backend.ToInt64(row.Values[0])
 		if err != nil {
 			return nil, err
 		}
 
 		timestamp := time.Unix(timeValue, 0)
 
+		rowValues := make(map[string]interface{}, len(row.Columns))
+		for i, column := range row.Columns {
+			rowValues[column] = row.Values[i]
+		}
+
+		tags, err := m.RenderTags(rowValues)
+		if err != nil {
+			return nil, err
+		}
+
 		// skip the columns[0], that's the timestamp
-		for i := 1; i < len(columns); i++ {
-			value, err := values[i].(json.Number).Float64()
+		for i := 1; i < len(row.Columns); i++ {
+			column := row.Columns[i]
+			if !targetColumns[column] {
+				continue // selected only to feed a tag template, not a metric
+			}
+
+			value, err := backend.ToFloat64(row.Values[i])
 			if err != nil {
 				return nil, err
 			}
 
-			point := c.newPoint(columns[i], timestamp, value)
-			points = append(points, point)
+			points = append(points, c.newPoint(column, timestamp, value, tags))
 		}
 	}
 
 	return points, nil
 }
 
-func (c *Converter) newPoint(name string, timestamp time.Time, value float64) (point client.Point) {
-	tags := map[string]string{
+func (c *Converter) newPoint(name string, timestamp time.Time, value float64, tags map[string]string) backend.Point {
+	pointTags := map[string]string{
 		"__name__": name,
 	}
 
-	for k, v := range defaultTags {
-		tags[k] = v
+	for k, v := range tags {
+		pointTags[k] = v
 	}
 
-	point = client.Point{
+	return backend.Point{
 		Measurement: name,
-		Tags:        tags,
+		Tags:        pointTags,
 		Fields: map[string]interface{}{
 			"value": value,
 		},
-		Time:      timestamp,
-		Precision: "s",
-	}
-
-	return point
-}
-
-func (c *Converter) WritePoints(points []client.Point) error {
-	batchPoints := client.BatchPoints{
-		Points:   points,
-		Database: *targetDB,
+		Time: timestamp,
 	}
-
-	_, err := c.TargetClient.Write(batchPoints)
-	return err
 }
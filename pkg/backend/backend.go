@@ -0,0 +1,48 @@
+// Package backend abstracts influx-converter's source and target databases
+// behind a pair of small interfaces, so the conversion pipeline doesn't need
+// to know whether it's talking to InfluxDB 1.x, InfluxDB 2.x, or a
+// Prometheus remote_write endpoint.
+package backend
+
+import (
+	"context"
+	"time"
+)
+
+// Row is a single row of a query result: the column names (shared across all
+// rows of a query) and their values in the same order. Err is set instead of
+// Columns/Values when the backend encountered an error while streaming; the
+// channel is closed right after such a row is sent.
+type Row struct {
+	Columns []string
+	Values  []interface{}
+	Err     error
+}
+
+// Point is a single converted metric, ready to be written to a target.
+type Point struct {
+	Measurement string
+	Tags        map[string]string
+	Fields      map[string]interface{}
+	Time        time.Time
+}
+
+// Source runs a query against a backend and streams back the resulting rows.
+// The returned channel is closed once all rows have been sent or ctx is
+// canceled.
+type Source interface {
+	Query(ctx context.Context, query string) (<-chan Row, error)
+}
+
+// Sink writes a batch of converted points to a backend.
+type Sink interface {
+	Write(ctx context.Context, points []Point) error
+}
+
+// SelfRetrying is implemented by Sink backends whose Write already retries
+// transient failures internally, with their own backoff. Callers driving a
+// generic retry loop around Sink.Write should type-assert for this and skip
+// their own retries, rather than compounding two independent backoff loops.
+type SelfRetrying interface {
+	SelfRetryingSink()
+}
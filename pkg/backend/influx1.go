@@ -0,0 +1,154 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+
+	"github.com/influxdata/influxdb1-client"
+)
+
+// Influx1Backend talks to an InfluxDB 1.x database using InfluxQL. It
+// implements both Source and Sink, matching influx-converter's original
+// (pre-pluggable-backend) behavior.
+type Influx1Backend struct {
+	Client    *client.Client
+	URL       url.URL
+	Username  string
+	Password  string
+	Database  string
+	ChunkSize int
+
+	httpClient *http.Client
+}
+
+// NewInflux1Backend dials an InfluxDB 1.x instance at rawURL authenticating
+// with username/password, scoped to database. chunkSize controls how many
+// rows per chunk Query fetches from the server at a time.
+func NewInflux1Backend(rawURL, username, password, database string, chunkSize int) (*Influx1Backend, error) {
+	host, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := client.NewClient(client.Config{
+		URL:       *host,
+		Username:  username,
+		Password:  password,
+		Precision: "s", // second precision is enough
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Influx1Backend{
+		Client:     c,
+		URL:        *host,
+		Username:   username,
+		Password:   password,
+		Database:   database,
+		ChunkSize:  chunkSize,
+		httpClient: &http.Client{},
+	}, nil
+}
+
+// Query runs an InfluxQL query using InfluxDB's chunked query API, fetching
+// ChunkSize rows from the server at a time instead of materializing the
+// whole result in memory, and streams the resulting rows as they arrive.
+//
+// client.Client (github.com/influxdata/influxdb1-client, not the /v2
+// package) has no chunked-query method of its own, so this drives the
+// "/query" endpoint by hand and hands the response body to the same
+// package's exported ChunkedResponse reader.
+func (b *Influx1Backend) Query(ctx context.Context, query string) (<-chan Row, error) {
+	endpoint := b.URL
+	endpoint.Path = path.Join(endpoint.Path, "query")
+
+	params := url.Values{}
+	params.Set("db", b.Database)
+	params.Set("q", query)
+	params.Set("chunked", "true")
+	params.Set("chunk_size", strconv.Itoa(b.ChunkSize))
+	params.Set("epoch", "s") // match the "s" Precision used everywhere else, so the time column comes back as a Unix-second int instead of an RFC3339 string
+	endpoint.RawQuery = params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if b.Username != "" {
+		req.SetBasicAuth(b.Username, b.Password)
+	}
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("querying influxdb: unexpected status %s", resp.Status)
+	}
+
+	chunkedResponse := client.NewChunkedResponse(resp.Body)
+
+	rows := make(chan Row)
+	go func() {
+		defer close(rows)
+		defer resp.Body.Close()
+
+		for {
+			response, err := chunkedResponse.NextResponse()
+			if err != nil {
+				if err != io.EOF {
+					rows <- Row{Err: err}
+				}
+				return
+			}
+
+			if response.Error() != nil {
+				rows <- Row{Err: response.Error()}
+				return
+			}
+
+			for _, result := range response.Results {
+				for _, series := range result.Series {
+					for _, values := range series.Values {
+						select {
+						case rows <- Row{Columns: series.Columns, Values: values}:
+						case <-ctx.Done():
+							return
+						}
+					}
+				}
+			}
+		}
+	}()
+
+	return rows, nil
+}
+
+// Write converts points into InfluxDB 1.x client points and writes them in a
+// single batch.
+func (b *Influx1Backend) Write(ctx context.Context, points []Point) error {
+	clientPoints := make([]client.Point, len(points))
+	for i, p := range points {
+		clientPoints[i] = client.Point{
+			Measurement: p.Measurement,
+			Tags:        p.Tags,
+			Fields:      p.Fields,
+			Time:        p.Time,
+			Precision:   "s",
+		}
+	}
+
+	_, err := b.Client.Write(client.BatchPoints{
+		Points:   clientPoints,
+		Database: b.Database,
+	})
+
+	return err
+}
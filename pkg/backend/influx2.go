@@ -0,0 +1,109 @@
+package backend
+
+import (
+	"context"
+	"sort"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+)
+
+// fluxMetaColumns are columns Flux's pivot() and grouping add to every
+// record that aren't metric fields, and so are never part of the rows this
+// package hands back to the conversion pipeline.
+var fluxMetaColumns = map[string]bool{
+	"_time":        true,
+	"result":       true,
+	"table":        true,
+	"_start":       true,
+	"_stop":        true,
+	"_measurement": true,
+}
+
+// Influx2Backend talks to an InfluxDB 2.x database using Flux queries and the
+// org/bucket/token auth model. It implements both Source and Sink.
+type Influx2Backend struct {
+	Client influxdb2.Client
+	Org    string
+	Bucket string
+}
+
+// NewInflux2Backend dials an InfluxDB 2.x instance at rawURL authenticating
+// with token, scoped to org/bucket.
+func NewInflux2Backend(rawURL, token, org, bucket string) *Influx2Backend {
+	return &Influx2Backend{
+		Client: influxdb2.NewClient(rawURL, token),
+		Org:    org,
+		Bucket: bucket,
+	}
+}
+
+// Query runs a Flux query and streams the resulting rows. Each row's first
+// column is always "_time", matching the convention the rest of the
+// conversion pipeline expects of the InfluxDB 1.x backend; the remaining
+// columns are sorted by name so a query's row shape is stable across calls,
+// since record.Values() iterates in random map order. Values come back as
+// whatever native Go type the Flux client decoded them as (int64, float64,
+// ...), not encoding/json.Number - callers needing a specific numeric type
+// should go through ToInt64/ToFloat64 rather than asserting directly.
+func (b *Influx2Backend) Query(ctx context.Context, query string) (<-chan Row, error) {
+	result, err := b.Client.QueryAPI(b.Org).Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make(chan Row)
+	go func() {
+		defer close(rows)
+
+		for result.Next() {
+			record := result.Record()
+
+			fieldNames := make([]string, 0, len(record.Values()))
+			for column := range record.Values() {
+				if fluxMetaColumns[column] {
+					continue
+				}
+				fieldNames = append(fieldNames, column)
+			}
+			sort.Strings(fieldNames)
+
+			columns := make([]string, 0, len(fieldNames)+1)
+			values := make([]interface{}, 0, len(fieldNames)+1)
+			columns = append(columns, "_time")
+			values = append(values, record.Time().Unix())
+			for _, column := range fieldNames {
+				columns = append(columns, column)
+				values = append(values, record.ValueByKey(column))
+			}
+
+			select {
+			case rows <- Row{Columns: columns, Values: values}:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		if result.Err() != nil {
+			select {
+			case rows <- Row{Err: result.Err()}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	return rows, nil
+}
+
+// Write converts points into InfluxDB 2.x line-protocol points and writes
+// them in a single blocking batch.
+func (b *Influx2Backend) Write(ctx context.Context, points []Point) error {
+	writeAPI := b.Client.WriteAPIBlocking(b.Org, b.Bucket)
+
+	influxPoints := make([]*write.Point, len(points))
+	for i, p := range points {
+		influxPoints[i] = influxdb2.NewPoint(p.Measurement, p.Tags, p.Fields, p.Time)
+	}
+
+	return writeAPI.WritePoint(ctx, influxPoints...)
+}
@@ -0,0 +1,52 @@
+package backend
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var lineProtocolEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
+
+// LineProtocol renders the point in InfluxDB line protocol, for --dry-run
+// output and debugging. Tags and fields are sorted by key for stable output.
+func (p Point) LineProtocol() string {
+	var sb strings.Builder
+
+	sb.WriteString(lineProtocolEscaper.Replace(p.Measurement))
+
+	for _, k := range sortedKeys(p.Tags) {
+		sb.WriteString(",")
+		sb.WriteString(lineProtocolEscaper.Replace(k))
+		sb.WriteString("=")
+		sb.WriteString(lineProtocolEscaper.Replace(p.Tags[k]))
+	}
+
+	sb.WriteString(" ")
+
+	fieldKeys := make([]string, 0, len(p.Fields))
+	for k := range p.Fields {
+		fieldKeys = append(fieldKeys, k)
+	}
+	sort.Strings(fieldKeys)
+
+	for i, k := range fieldKeys {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		fmt.Fprintf(&sb, "%s=%v", lineProtocolEscaper.Replace(k), p.Fields[k])
+	}
+
+	fmt.Fprintf(&sb, " %d", p.Time.UnixNano())
+
+	return sb.String()
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
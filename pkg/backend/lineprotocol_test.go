@@ -0,0 +1,59 @@
+package backend
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPointLineProtocol(t *testing.T) {
+	p := Point{
+		Measurement: "cpu_usage",
+		Tags: map[string]string{
+			"host":   "server01",
+			"region": "us-west",
+		},
+		Fields: map[string]interface{}{
+			"value": 64.5,
+		},
+		Time: time.Unix(0, 1609459200000000000),
+	}
+
+	want := "cpu_usage,host=server01,region=us-west value=64.5 1609459200000000000"
+	if got := p.LineProtocol(); got != want {
+		t.Errorf("LineProtocol() = %q, want %q", got, want)
+	}
+}
+
+func TestPointLineProtocolEscaping(t *testing.T) {
+	p := Point{
+		Measurement: "disk usage",
+		Tags: map[string]string{
+			"path": "/var/log,temp",
+		},
+		Fields: map[string]interface{}{
+			"value": 1,
+		},
+		Time: time.Unix(0, 0),
+	}
+
+	want := `disk\ usage,path=/var/log\,temp value=1 0`
+	if got := p.LineProtocol(); got != want {
+		t.Errorf("LineProtocol() = %q, want %q", got, want)
+	}
+}
+
+func TestPointLineProtocolMultipleFieldsSorted(t *testing.T) {
+	p := Point{
+		Measurement: "m",
+		Fields: map[string]interface{}{
+			"z": 1,
+			"a": 2,
+		},
+		Time: time.Unix(0, 0),
+	}
+
+	want := "m a=2,z=1 0"
+	if got := p.LineProtocol(); got != want {
+		t.Errorf("LineProtocol() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,61 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/grdl/influx-converter/pkg/remotewrite"
+)
+
+// RemoteWriteSink writes points to a Prometheus remote_write endpoint. It
+// implements Sink only: a remote_write endpoint is write-only, so it can
+// never act as a Source.
+type RemoteWriteSink struct {
+	Client *remotewrite.Client
+}
+
+// NewRemoteWriteSink targets the given remote_write URL.
+func NewRemoteWriteSink(url string) *RemoteWriteSink {
+	return &RemoteWriteSink{Client: remotewrite.NewClient(url)}
+}
+
+// SelfRetryingSink marks RemoteWriteSink as implementing backend.SelfRetrying:
+// remotewrite.Client.Write already retries transient failures with its own
+// backoff, honoring the endpoint's Retry-After header.
+func (s *RemoteWriteSink) SelfRetryingSink() {}
+
+// Write converts each point's tags and float64 "value" field into a
+// single-sample Prometheus TimeSeries and ships the batch as one
+// WriteRequest.
+func (s *RemoteWriteSink) Write(ctx context.Context, points []Point) error {
+	wr := remotewrite.WriteRequest{
+		Timeseries: make([]remotewrite.TimeSeries, 0, len(points)),
+	}
+
+	for _, p := range points {
+		labels := make([]remotewrite.Label, 0, len(p.Tags))
+		for k, v := range p.Tags {
+			labels = append(labels, remotewrite.Label{Name: k, Value: v})
+		}
+		sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+
+		value, ok := p.Fields["value"].(float64)
+		if !ok {
+			return fmt.Errorf("remote_write: point %q has no float64 \"value\" field", p.Measurement)
+		}
+
+		wr.Timeseries = append(wr.Timeseries, remotewrite.TimeSeries{
+			Labels: labels,
+			Samples: []remotewrite.Sample{
+				{
+					Value:     value,
+					Timestamp: p.Time.UnixNano() / int64(time.Millisecond),
+				},
+			},
+		})
+	}
+
+	return s.Client.Write(ctx, wr)
+}
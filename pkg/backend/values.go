@@ -0,0 +1,43 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ToInt64 and ToFloat64 normalize a Row value to a concrete numeric type,
+// regardless of how the backend that produced it encoded numbers: InfluxDB
+// 1.x rows are decoded from JSON and carry encoding/json.Number, while
+// InfluxDB 2.x's Flux client hands back native int64/float64 values.
+
+// ToInt64 normalizes v to int64.
+func ToInt64(v interface{}) (int64, error) {
+	switch v := v.(type) {
+	case json.Number:
+		return v.Int64()
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case float64:
+		return int64(v), nil
+	default:
+		return 0, fmt.Errorf("cannot convert %T to int64", v)
+	}
+}
+
+// ToFloat64 normalizes v to float64.
+func ToFloat64(v interface{}) (float64, error) {
+	switch v := v.(type) {
+	case json.Number:
+		return v.Float64()
+	case float64:
+		return v, nil
+	case int64:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	default:
+		return 0, fmt.Errorf("cannot convert %T to float64", v)
+	}
+}
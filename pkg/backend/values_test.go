@@ -0,0 +1,64 @@
+package backend
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestToInt64(t *testing.T) {
+	cases := []struct {
+		name string
+		in   interface{}
+		want int64
+	}{
+		{"json.Number", json.Number("42"), 42},
+		{"int64", int64(42), 42},
+		{"int", 42, 42},
+		{"float64", float64(42), 42},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ToInt64(tc.in)
+			if err != nil {
+				t.Fatalf("ToInt64(%v): %v", tc.in, err)
+			}
+			if got != tc.want {
+				t.Errorf("ToInt64(%v) = %d, want %d", tc.in, got, tc.want)
+			}
+		})
+	}
+
+	if _, err := ToInt64("not a number"); err == nil {
+		t.Error("ToInt64(string) should error")
+	}
+}
+
+func TestToFloat64(t *testing.T) {
+	cases := []struct {
+		name string
+		in   interface{}
+		want float64
+	}{
+		{"json.Number", json.Number("1.5"), 1.5},
+		{"float64", 1.5, 1.5},
+		{"int64", int64(2), 2},
+		{"int", 2, 2},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ToFloat64(tc.in)
+			if err != nil {
+				t.Fatalf("ToFloat64(%v): %v", tc.in, err)
+			}
+			if got != tc.want {
+				t.Errorf("ToFloat64(%v) = %v, want %v", tc.in, got, tc.want)
+			}
+		})
+	}
+
+	if _, err := ToFloat64("not a number"); err == nil {
+		t.Error("ToFloat64(string) should error")
+	}
+}
@@ -0,0 +1,200 @@
+// Package mapping loads user-defined source-to-target migration mappings
+// from a YAML config file, replacing influx-converter's original hardcoded
+// Nest/Pronestheus column list with a declarative, reusable schema.
+package mapping
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the top-level --config file: a list of independent migrations,
+// each producing its own source query against its own measurement.
+type Config struct {
+	Mappings []Mapping `yaml:"mappings"`
+}
+
+// Mapping declares how to migrate a single source measurement: which
+// columns to select and rename, an optional filter, and the tags (static or
+// templated from row columns) to attach to every point it produces.
+type Mapping struct {
+	// Measurement is the source measurement to select from.
+	Measurement string `yaml:"measurement"`
+	// Where is an optional filter, without the "where" keyword: InfluxQL for
+	// an InfluxDB 1.x source, a Flux predicate for an InfluxDB 2.x source.
+	Where string `yaml:"where"`
+	// Columns maps source column names to target measurement names.
+	Columns map[string]string `yaml:"columns"`
+	// Tags are static tags attached to every point this mapping produces.
+	Tags map[string]string `yaml:"tags"`
+	// TagTemplates are Go text/template strings, rendered per row, that can
+	// reference TemplateColumns by name (e.g. "{{.host}}:{{.port}}").
+	TagTemplates map[string]string `yaml:"tag_templates"`
+	// TemplateColumns lists the raw source columns TagTemplates reference,
+	// so they can be selected alongside the metric columns.
+	TemplateColumns []string `yaml:"template_columns"`
+
+	compiledTagTemplates map[string]*template.Template
+}
+
+// Load reads and parses a mapping config file, compiling its tag templates.
+func Load(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+
+	for i := range cfg.Mappings {
+		if err := cfg.Mappings[i].compile(); err != nil {
+			return nil, fmt.Errorf("mapping %q: %w", cfg.Mappings[i].Measurement, err)
+		}
+	}
+
+	return &cfg, nil
+}
+
+func (m *Mapping) compile() error {
+	m.compiledTagTemplates = make(map[string]*template.Template, len(m.TagTemplates))
+
+	for tag, tmplText := range m.TagTemplates {
+		tmpl, err := template.New(tag).Parse(tmplText)
+		if err != nil {
+			return fmt.Errorf("tag template %q: %w", tag, err)
+		}
+		m.compiledTagTemplates[tag] = tmpl
+	}
+
+	return nil
+}
+
+// RenderTags returns the static tags merged with the tag templates, rendered
+// against row - a map of raw source column values keyed by column name.
+func (m *Mapping) RenderTags(row map[string]interface{}) (map[string]string, error) {
+	tags := make(map[string]string, len(m.Tags)+len(m.compiledTagTemplates))
+	for k, v := range m.Tags {
+		tags[k] = v
+	}
+
+	for tag, tmpl := range m.compiledTagTemplates {
+		var buf strings.Builder
+		if err := tmpl.Execute(&buf, row); err != nil {
+			return nil, fmt.Errorf("rendering tag %q: %w", tag, err)
+		}
+		tags[tag] = buf.String()
+	}
+
+	return tags, nil
+}
+
+// TargetColumns returns the set of target measurement names this mapping
+// produces, used to tell metric columns apart from raw columns selected only
+// to feed tag templates.
+func (m *Mapping) TargetColumns() map[string]bool {
+	targets := make(map[string]bool, len(m.Columns))
+	for _, target := range m.Columns {
+		targets[target] = true
+	}
+	return targets
+}
+
+// sortedSourceColumns returns this mapping's source column names in
+// deterministic order, so the generated queries are stable.
+func (m *Mapping) sortedSourceColumns() []string {
+	sourceColumns := make([]string, 0, len(m.Columns))
+	for source := range m.Columns {
+		sourceColumns = append(sourceColumns, source)
+	}
+	sort.Strings(sourceColumns)
+	return sourceColumns
+}
+
+// SelectQuery builds the InfluxQL query selecting and renaming this
+// mapping's metric columns, plus any raw columns its tag templates need. Use
+// this against an InfluxDB 1.x backend; InfluxDB 2.x speaks Flux instead,
+// see FluxSelectQuery.
+func (m *Mapping) SelectQuery() string {
+	sourceColumns := m.sortedSourceColumns()
+
+	selects := make([]string, 0, len(sourceColumns)+len(m.TemplateColumns))
+	for _, source := range sourceColumns {
+		selects = append(selects, fmt.Sprintf("%s as %s", source, m.Columns[source]))
+	}
+	for _, source := range m.TemplateColumns {
+		selects = append(selects, source)
+	}
+
+	query := fmt.Sprintf("select %s from %s", strings.Join(selects, ", "), m.Measurement)
+	if m.Where != "" {
+		query += " where " + m.Where
+	}
+
+	return query
+}
+
+// CountQuery builds the matching `select count(*)` InfluxQL query, used to
+// log the expected row count and estimate the ETA against an InfluxDB 1.x
+// backend.
+func (m *Mapping) CountQuery() string {
+	query := fmt.Sprintf("select count(*) from %s", m.Measurement)
+	if m.Where != "" {
+		query += " where " + m.Where
+	}
+
+	return query
+}
+
+// FluxSelectQuery builds the Flux equivalent of SelectQuery, for use against
+// an InfluxDB 2.x backend's bucket. Where, if set, must already be a valid
+// Flux predicate (e.g. `r._time > now() - 10h`) rather than InfluxQL, since
+// the two query languages aren't source-compatible.
+func (m *Mapping) FluxSelectQuery(bucket string) string {
+	fields := append(append([]string{}, m.sortedSourceColumns()...), m.TemplateColumns...)
+
+	fieldFilters := make([]string, len(fields))
+	for i, field := range fields {
+		fieldFilters[i] = fmt.Sprintf("r._field == %q", field)
+	}
+
+	renames := make([]string, 0, len(m.Columns))
+	for _, source := range m.sortedSourceColumns() {
+		renames = append(renames, fmt.Sprintf("%q: %q", source, m.Columns[source]))
+	}
+
+	var query strings.Builder
+	fmt.Fprintf(&query, "from(bucket: %q)", bucket)
+	query.WriteString(` |> range(start: 0)`)
+	fmt.Fprintf(&query, ` |> filter(fn: (r) => r._measurement == %q)`, m.Measurement)
+	if m.Where != "" {
+		fmt.Fprintf(&query, ` |> filter(fn: (r) => %s)`, m.Where)
+	}
+	fmt.Fprintf(&query, ` |> filter(fn: (r) => %s)`, strings.Join(fieldFilters, " or "))
+	query.WriteString(` |> pivot(rowKey: ["_time"], columnKey: ["_field"], valueColumn: "_value")`)
+	fmt.Fprintf(&query, ` |> rename(columns: {%s})`, strings.Join(renames, ", "))
+
+	return query.String()
+}
+
+// FluxCountQuery builds the Flux equivalent of CountQuery, for use against an
+// InfluxDB 2.x backend's bucket.
+func (m *Mapping) FluxCountQuery(bucket string) string {
+	var query strings.Builder
+	fmt.Fprintf(&query, "from(bucket: %q)", bucket)
+	query.WriteString(` |> range(start: 0)`)
+	fmt.Fprintf(&query, ` |> filter(fn: (r) => r._measurement == %q)`, m.Measurement)
+	if m.Where != "" {
+		fmt.Fprintf(&query, ` |> filter(fn: (r) => %s)`, m.Where)
+	}
+	query.WriteString(` |> count()`)
+
+	return query.String()
+}
@@ -0,0 +1,108 @@
+package mapping
+
+import "testing"
+
+func TestSelectQuery(t *testing.T) {
+	m := Mapping{
+		Measurement: "nest",
+		Where:       "time > now() - 1h",
+		Columns: map[string]string{
+			"temperature": "nest_temperature",
+			"humidity":    "nest_humidity",
+		},
+		TemplateColumns: []string{"device_id"},
+	}
+
+	want := "select humidity as nest_humidity, temperature as nest_temperature, device_id from nest where time > now() - 1h"
+	if got := m.SelectQuery(); got != want {
+		t.Errorf("SelectQuery() = %q, want %q", got, want)
+	}
+}
+
+func TestCountQuery(t *testing.T) {
+	m := Mapping{Measurement: "nest", Where: "time > now() - 1h"}
+
+	want := "select count(*) from nest where time > now() - 1h"
+	if got := m.CountQuery(); got != want {
+		t.Errorf("CountQuery() = %q, want %q", got, want)
+	}
+}
+
+func TestCountQueryNoWhere(t *testing.T) {
+	m := Mapping{Measurement: "nest"}
+
+	want := "select count(*) from nest"
+	if got := m.CountQuery(); got != want {
+		t.Errorf("CountQuery() = %q, want %q", got, want)
+	}
+}
+
+func TestFluxSelectQuery(t *testing.T) {
+	m := Mapping{
+		Measurement: "nest",
+		Columns: map[string]string{
+			"temperature": "nest_temperature",
+		},
+	}
+
+	want := `from(bucket: "metrics") |> range(start: 0) |> filter(fn: (r) => r._measurement == "nest") |> filter(fn: (r) => r._field == "temperature") |> pivot(rowKey: ["_time"], columnKey: ["_field"], valueColumn: "_value") |> rename(columns: {"temperature": "nest_temperature"})`
+	if got := m.FluxSelectQuery("metrics"); got != want {
+		t.Errorf("FluxSelectQuery() = %q, want %q", got, want)
+	}
+}
+
+func TestFluxCountQuery(t *testing.T) {
+	m := Mapping{Measurement: "nest"}
+
+	want := `from(bucket: "metrics") |> range(start: 0) |> filter(fn: (r) => r._measurement == "nest") |> count()`
+	if got := m.FluxCountQuery("metrics"); got != want {
+		t.Errorf("FluxCountQuery() = %q, want %q", got, want)
+	}
+}
+
+func TestTargetColumns(t *testing.T) {
+	m := Mapping{
+		Columns: map[string]string{
+			"temperature": "nest_temperature",
+			"humidity":    "nest_humidity",
+		},
+	}
+
+	targets := m.TargetColumns()
+	if !targets["nest_temperature"] || !targets["nest_humidity"] {
+		t.Errorf("TargetColumns() = %v, want both nest_temperature and nest_humidity", targets)
+	}
+	if len(targets) != 2 {
+		t.Errorf("TargetColumns() has %d entries, want 2", len(targets))
+	}
+}
+
+func TestRenderTags(t *testing.T) {
+	cfg := &Config{
+		Mappings: []Mapping{
+			{
+				Measurement: "nest",
+				Tags:        map[string]string{"source": "nest"},
+				TagTemplates: map[string]string{
+					"device": "{{.device_id}}",
+				},
+				TemplateColumns: []string{"device_id"},
+			},
+		},
+	}
+	if err := cfg.Mappings[0].compile(); err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+
+	tags, err := cfg.Mappings[0].RenderTags(map[string]interface{}{"device_id": "abc123"})
+	if err != nil {
+		t.Fatalf("RenderTags: %v", err)
+	}
+
+	if tags["source"] != "nest" {
+		t.Errorf("tags[source] = %q, want %q", tags["source"], "nest")
+	}
+	if tags["device"] != "abc123" {
+		t.Errorf("tags[device] = %q, want %q", tags["device"], "abc123")
+	}
+}
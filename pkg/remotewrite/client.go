@@ -0,0 +1,102 @@
+package remotewrite
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/golang/snappy"
+)
+
+const (
+	maxRetries     = 5
+	initialBackoff = 500 * time.Millisecond
+	maxBackoff     = 30 * time.Second
+)
+
+// Client POSTs WriteRequest batches to a Prometheus remote_write endpoint.
+type Client struct {
+	URL        string
+	HTTPClient *http.Client
+}
+
+// NewClient returns a Client targeting the given remote_write URL.
+func NewClient(url string) *Client {
+	return &Client{
+		URL:        url,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Write snappy-compresses the WriteRequest and POSTs it to the remote_write
+// endpoint, retrying with exponential backoff on 5xx responses and honoring
+// a server-provided Retry-After on 429.
+func (c *Client) Write(ctx context.Context, wr WriteRequest) error {
+	compressed := snappy.Encode(nil, wr.Marshal())
+
+	backoff := initialBackoff
+	var lastErr error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			backoff = time.Duration(math.Min(float64(backoff*2), float64(maxBackoff)))
+		}
+
+		retryAfter, retryable, err := c.post(ctx, compressed)
+		if err == nil {
+			return nil
+		}
+		if !retryable {
+			return err
+		}
+		lastErr = err
+
+		if retryAfter > 0 {
+			backoff = retryAfter
+		}
+	}
+
+	return fmt.Errorf("remote_write: giving up after %d attempts: %w", maxRetries+1, lastErr)
+}
+
+// post issues a single POST attempt. It returns a non-nil error on any
+// non-2xx response; retryable reports whether the caller should retry
+// (5xx, 429, and requests that failed before any response). When the response is a 429 with a Retry-After header,
+// that duration is returned alongside the error so the caller can honor it.
+func (c *Client) post(ctx context.Context, compressed []byte) (retryAfter time.Duration, retryable bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(compressed))
+	if err != nil {
+		return 0, false, err
+	}
+
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return 0, true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		return 0, false, nil
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			retryAfter = time.Duration(seconds) * time.Second
+		}
+		return retryAfter, true, fmt.Errorf("remote_write: server returned %s", resp.Status)
+	}
+
+	return 0, resp.StatusCode/100 == 5, fmt.Errorf("remote_write: server returned %s", resp.Status)
+}
@@ -0,0 +1,135 @@
+package remotewrite
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+)
+
+func TestPostSuccess(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL)
+	retryAfter, retryable, err := c.post(context.Background(), snappy.Encode(nil, []byte("payload")))
+	if err != nil {
+		t.Fatalf("post() error = %v", err)
+	}
+	if retryable {
+		t.Error("post() retryable = true on 200, want false")
+	}
+	if retryAfter != 0 {
+		t.Errorf("post() retryAfter = %v, want 0", retryAfter)
+	}
+}
+
+func TestPostRetryableServerError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL)
+	_, retryable, err := c.post(context.Background(), snappy.Encode(nil, []byte("payload")))
+	if err == nil {
+		t.Fatal("post() error = nil on 503, want non-nil")
+	}
+	if !retryable {
+		t.Error("post() retryable = false on 503, want true")
+	}
+}
+
+func TestPostNonRetryableClientError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL)
+	_, retryable, err := c.post(context.Background(), snappy.Encode(nil, []byte("payload")))
+	if err == nil {
+		t.Fatal("post() error = nil on 400, want non-nil")
+	}
+	if retryable {
+		t.Error("post() retryable = true on 400, want false")
+	}
+}
+
+func TestPostTooManyRequestsHonorsRetryAfter(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "2")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL)
+	retryAfter, retryable, err := c.post(context.Background(), snappy.Encode(nil, []byte("payload")))
+	if err == nil {
+		t.Fatal("post() error = nil on 429, want non-nil")
+	}
+	if !retryable {
+		t.Error("post() retryable = false on 429, want true")
+	}
+	if retryAfter != 2*time.Second {
+		t.Errorf("post() retryAfter = %v, want 2s", retryAfter)
+	}
+}
+
+func TestWriteRetriesThenSucceeds(t *testing.T) {
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL)
+	if err := c.Write(context.Background(), WriteRequest{}); err != nil {
+		t.Fatalf("Write() error = %v, want nil after retrying", err)
+	}
+	if got := atomic.LoadInt32(&attempts); got != 2 {
+		t.Errorf("server saw %d attempts, want 2", got)
+	}
+}
+
+func TestWriteGivesUpOnPersistentFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	c := NewClient(srv.URL)
+	if err := c.Write(ctx, WriteRequest{}); err == nil {
+		t.Fatal("Write() error = nil on persistent 503, want non-nil")
+	}
+}
+
+func TestWriteNonRetryableFailsImmediately(t *testing.T) {
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL)
+	if err := c.Write(context.Background(), WriteRequest{}); err == nil {
+		t.Fatal("Write() error = nil on 400, want non-nil")
+	}
+	if got := atomic.LoadInt32(&attempts); got != 1 {
+		t.Errorf("server saw %d attempts, want 1 (no retry on non-retryable error)", got)
+	}
+}
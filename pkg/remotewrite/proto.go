@@ -0,0 +1,91 @@
+package remotewrite
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Hand-rolled proto3 marshaling for the WriteRequest/TimeSeries/Label/Sample
+// messages, matching the wire layout of prometheus/prompb's generated code:
+//
+//	message Sample      { double value = 1; int64 timestamp = 2; }
+//	message Label       { string name = 1; string value = 2; }
+//	message TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	message WriteRequest { repeated TimeSeries timeseries = 1; }
+//
+// There's no protoc toolchain available in this environment, so the wire
+// format is produced directly instead of via generated code.
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+func appendTag(buf []byte, fieldNum int, wireType int) []byte {
+	return appendVarint(buf, uint64(fieldNum)<<3|uint64(wireType))
+}
+
+func appendVarint(buf []byte, v uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	return append(buf, tmp[:n]...)
+}
+
+func appendFixed64(buf []byte, v uint64) []byte {
+	var tmp [8]byte
+	binary.LittleEndian.PutUint64(tmp[:], v)
+	return append(buf, tmp[:]...)
+}
+
+func appendString(buf []byte, fieldNum int, s string) []byte {
+	buf = appendTag(buf, fieldNum, wireBytes)
+	buf = appendVarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+func appendBytes(buf []byte, fieldNum int, b []byte) []byte {
+	buf = appendTag(buf, fieldNum, wireBytes)
+	buf = appendVarint(buf, uint64(len(b)))
+	return append(buf, b...)
+}
+
+// Marshal encodes the Sample as a proto3 message.
+func (s Sample) Marshal() []byte {
+	var buf []byte
+	buf = appendTag(buf, 1, wireFixed64)
+	buf = appendFixed64(buf, math.Float64bits(s.Value))
+	buf = appendTag(buf, 2, wireVarint)
+	buf = appendVarint(buf, uint64(s.Timestamp))
+	return buf
+}
+
+// Marshal encodes the Label as a proto3 message.
+func (l Label) Marshal() []byte {
+	var buf []byte
+	buf = appendString(buf, 1, l.Name)
+	buf = appendString(buf, 2, l.Value)
+	return buf
+}
+
+// Marshal encodes the TimeSeries as a proto3 message.
+func (ts TimeSeries) Marshal() []byte {
+	var buf []byte
+	for _, l := range ts.Labels {
+		buf = appendBytes(buf, 1, l.Marshal())
+	}
+	for _, s := range ts.Samples {
+		buf = appendBytes(buf, 2, s.Marshal())
+	}
+	return buf
+}
+
+// Marshal encodes the WriteRequest as a proto3 message, ready to be
+// snappy-compressed and POSTed to a remote_write endpoint.
+func (wr WriteRequest) Marshal() []byte {
+	var buf []byte
+	for _, ts := range wr.Timeseries {
+		buf = appendBytes(buf, 1, ts.Marshal())
+	}
+	return buf
+}
@@ -0,0 +1,92 @@
+package remotewrite
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+func TestSampleMarshal(t *testing.T) {
+	s := Sample{Value: 3.5, Timestamp: 1609459200000}
+	got := s.Marshal()
+
+	var want []byte
+	want = append(want, byte(1<<3|1)) // field 1, wireFixed64
+	var v [8]byte
+	binary.LittleEndian.PutUint64(v[:], math.Float64bits(3.5))
+	want = append(want, v[:]...)
+	want = append(want, byte(2<<3|0)) // field 2, wireVarint
+	var ts [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(ts[:], uint64(1609459200000))
+	want = append(want, ts[:n]...)
+
+	if string(got) != string(want) {
+		t.Errorf("Sample.Marshal() = %x, want %x", got, want)
+	}
+}
+
+func TestLabelMarshal(t *testing.T) {
+	l := Label{Name: "__name__", Value: "up"}
+	got := l.Marshal()
+
+	var want []byte
+	want = append(want, byte(1<<3|2)) // field 1, wireBytes
+	want = append(want, byte(len(l.Name)))
+	want = append(want, l.Name...)
+	want = append(want, byte(2<<3|2)) // field 2, wireBytes
+	want = append(want, byte(len(l.Value)))
+	want = append(want, l.Value...)
+
+	if string(got) != string(want) {
+		t.Errorf("Label.Marshal() = %x, want %x", got, want)
+	}
+}
+
+func TestTimeSeriesMarshal(t *testing.T) {
+	ts := TimeSeries{
+		Labels:  []Label{{Name: "__name__", Value: "up"}},
+		Samples: []Sample{{Value: 1, Timestamp: 1000}},
+	}
+	got := ts.Marshal()
+
+	label := Label{Name: "__name__", Value: "up"}.Marshal()
+	sample := Sample{Value: 1, Timestamp: 1000}.Marshal()
+
+	var want []byte
+	want = append(want, byte(1<<3|2))
+	want = append(want, byte(len(label)))
+	want = append(want, label...)
+	want = append(want, byte(2<<3|2))
+	want = append(want, byte(len(sample)))
+	want = append(want, sample...)
+
+	if string(got) != string(want) {
+		t.Errorf("TimeSeries.Marshal() = %x, want %x", got, want)
+	}
+}
+
+func TestWriteRequestMarshalEmpty(t *testing.T) {
+	wr := WriteRequest{}
+	if got := wr.Marshal(); len(got) != 0 {
+		t.Errorf("WriteRequest{}.Marshal() = %x, want empty", got)
+	}
+}
+
+func TestWriteRequestMarshal(t *testing.T) {
+	series := TimeSeries{
+		Labels:  []Label{{Name: "job", Value: "node"}},
+		Samples: []Sample{{Value: 42, Timestamp: 5}},
+	}
+	wr := WriteRequest{Timeseries: []TimeSeries{series}}
+	got := wr.Marshal()
+
+	seriesBytes := series.Marshal()
+	var want []byte
+	want = append(want, byte(1<<3|2))
+	want = append(want, byte(len(seriesBytes)))
+	want = append(want, seriesBytes...)
+
+	if string(got) != string(want) {
+		t.Errorf("WriteRequest.Marshal() = %x, want %x", got, want)
+	}
+}
@@ -0,0 +1,33 @@
+// Package remotewrite implements a minimal client for the Prometheus
+// remote_write protocol (https://prometheus.io/docs/concepts/remote_write_spec/),
+// so that influx-converter can ship samples directly to a Prometheus-compatible
+// backend instead of InfluxDB.
+package remotewrite
+
+// WriteRequest is the top-level message POSTed to a remote_write endpoint.
+// It mirrors prometheus/prompb.WriteRequest, but only carries the fields
+// influx-converter actually produces.
+type WriteRequest struct {
+	Timeseries []TimeSeries
+}
+
+// TimeSeries is a single Prometheus series: a set of labels plus the samples
+// belonging to it.
+type TimeSeries struct {
+	Labels  []Label
+	Samples []Sample
+}
+
+// Label is a Prometheus label pair. The `__name__` label carries the metric
+// name, matching the convention influx-converter already uses for the
+// InfluxDB `__name__` tag.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is a single float64 value at a millisecond-precision Unix timestamp.
+type Sample struct {
+	Value     float64
+	Timestamp int64 // milliseconds since the Unix epoch
+}
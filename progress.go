@@ -0,0 +1,42 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// progressTracker reports per-batch write throughput and an ETA for the
+// remaining rows of a RunOnTable run, across however many writer goroutines
+// are draining batches concurrently.
+type progressTracker struct {
+	mu        sync.Mutex
+	totalRows float64
+	doneRows  float64
+	start     time.Time
+}
+
+func newProgressTracker(totalRows float64) *progressTracker {
+	return &progressTracker{totalRows: totalRows, start: time.Now()}
+}
+
+// reportBatch records a completed batch write and logs its throughput plus
+// the overall ETA based on the average rate observed so far.
+func (p *progressTracker) reportBatch(worker int, rows int, elapsed time.Duration) {
+	p.mu.Lock()
+	p.doneRows += float64(rows)
+	done, total := p.doneRows, p.totalRows
+	overallElapsed := time.Since(p.start)
+	p.mu.Unlock()
+
+	batchRate := float64(rows) / elapsed.Seconds()
+	overallRate := done / overallElapsed.Seconds()
+
+	eta := "unknown"
+	if overallRate > 0 && total > done {
+		eta = time.Duration((total - done) / overallRate * float64(time.Second)).Round(time.Second).String()
+	}
+
+	log.Printf("worker %d: wrote %d rows in %s (%.0f rows/s) - %.0f/%.0f done, ETA %s\n",
+		worker, rows, elapsed.Round(time.Millisecond), batchRate, done, total, eta)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"sync"
+)
+
+// verifyReport accumulates per-measurement written/expected row counts
+// across the whole run, for --verify's final summary.
+type verifyReport struct {
+	mu       sync.Mutex
+	written  map[string]int
+	expected map[string]int
+}
+
+func newVerifyReport() *verifyReport {
+	return &verifyReport{
+		written:  make(map[string]int),
+		expected: make(map[string]int),
+	}
+}
+
+func (r *verifyReport) record(measurement string, written, expected int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.written[measurement] += written
+	r.expected[measurement] += expected
+}
+
+// log prints the written vs. expected row count for every measurement seen
+// during the run, flagging any mismatch as likely silent data loss.
+func (r *verifyReport) log() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	measurements := make([]string, 0, len(r.written))
+	for measurement := range r.written {
+		measurements = append(measurements, measurement)
+	}
+	sort.Strings(measurements)
+
+	log.Println("-------------------------------")
+	log.Println("verify: per-measurement written vs. target row counts:")
+	for _, measurement := range measurements {
+		written, expected := r.written[measurement], r.expected[measurement]
+
+		status := "OK"
+		if written != expected {
+			status = "MISMATCH"
+		}
+
+		log.Printf("  %s: wrote %d, target reports %d [%s]\n", measurement, written, expected, status)
+	}
+}